@@ -3,20 +3,20 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"os"
+	"strings"
 	"time"
 
 	"runtime/debug"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.viam.com/rdk/components/board"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/robot/client"
-
 	"go.viam.com/utils/rpc"
-	"gonum.org/v1/gonum/stat"
+
+	"go.viam.com/micro-rdk/canary/migrations"
 )
 
 type connectionStats struct {
@@ -26,69 +26,89 @@ type connectionStats struct {
 	connectionError     string
 }
 
-type boardAPIStats struct {
-	successes          int
-	failures           int
-	avgLatencyMs       float64
-	avgLatencyMsStdDev float64
-	connectionError    string
-}
-
 func main() {
+	configPath := flag.String("config", "canary_config.yaml", "path to the YAML/JSON component test matrix")
+	componentsFlag := flag.String("components", "", "comma-separated subset of component names to test (default: all configured components)")
+	flag.Parse()
+
 	logger := logging.NewDebugLogger("canary")
 	ctx := context.Background()
 	runTimestamp := time.Now()
-	mongodb_uri := os.Getenv("MONGODB_TEST_OUTPUT_URI")
-	mongo_client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongodb_uri))
+
+	cfg, err := loadConfig(*configPath)
 	if err != nil {
 		logger.Error(err)
 		return
 	}
-	defer func() {
-		if err := mongo_client.Disconnect(ctx); err != nil {
-			panic(err)
-		}
-	}()
-	coll := mongo_client.Database("micrordk_canary").Collection("raw_results")
-
-	machine, connStats, err := tryConnect(ctx, logger)
-	if err != nil {
-		record, err := buildRecord(runTimestamp, connStats, boardAPIStats{})
-		if err != nil {
-			logger.Error(err)
-			return
-		}
-		if _, err := coll.InsertOne(ctx, record); err != nil {
-			logger.Error("could not upload canary result to database")
-		}
-		logger.Fatal(err)
+	var selected []string
+	if *componentsFlag != "" {
+		selected = strings.Split(*componentsFlag, ",")
 	}
-	defer machine.Close(ctx)
+	cfg = filterComponents(cfg, selected)
 
-	board, err := board.FromRobot(machine, "board")
+	tests, err := buildComponentTests(cfg)
 	if err != nil {
 		logger.Error(err)
 		return
 	}
 
-	pin, err := board.GPIOPinByName("32")
+	componentNames := make([]string, 0, len(cfg.Components))
+	for _, c := range cfg.Components {
+		componentNames = append(componentNames, c.ComponentName)
+	}
+
+	sinks, mongoColl := buildSinks(ctx, logger)
+	defer closeSinks(ctx, logger, sinks)
+
+	machine, connStats, err := tryConnect(ctx, logger)
 	if err != nil {
-		logger.Error(err)
-		return
+		record, buildErr := buildRecord(runTimestamp, connStats, nil)
+		if buildErr != nil {
+			logger.Error(buildErr)
+			return
+		}
+		annotateAndPublish(ctx, logger, sinks, mongoColl, record, componentNames)
+		// logger.Fatal calls os.Exit internally, which skips the deferred
+		// closeSinks above, so flush explicitly or the connection-failure
+		// telemetry this run exists to report never leaves the process.
+		closeSinks(ctx, logger, sinks)
+		logger.Fatal(err)
 	}
+	defer machine.Close(ctx)
 
-	boardStats := boardAPItest(ctx, pin)
+	componentStats := runComponentTests(ctx, machine, tests)
 
-	record, err := buildRecord(runTimestamp, connStats, boardStats)
+	record, err := buildRecord(runTimestamp, connStats, componentStats)
 	if err != nil {
 		logger.Error(err)
 		return
 	}
-	if _, err := coll.InsertOne(ctx, record); err != nil {
-		logger.Error("could not upload canary result to database")
+	if annotateAndPublish(ctx, logger, sinks, mongoColl, record, componentNames) {
+		logger.Error("canary run flagged as a regression against prior runs")
+		// os.Exit skips deferred calls, so flush explicitly before exiting
+		// or the regression run's telemetry never leaves the process.
+		closeSinks(ctx, logger, sinks)
+		os.Exit(1)
 	}
 }
 
+// annotateAndPublish decorates record with regression-detection fields (when
+// the mongo sink is active and has history to compare against) and publishes
+// it to every configured sink. It returns whether a regression was flagged.
+func annotateAndPublish(ctx context.Context, logger logging.Logger, sinks []ResultSink, mongoColl *mongo.Collection, record bson.M, componentNames []string) bool {
+	sdkVersion, _ := record["sdk_version"].(string)
+	regressed := false
+	if mongoColl != nil && sdkVersion != "" {
+		var err error
+		regressed, err = annotateRegressions(ctx, mongoColl, record, sdkVersion, componentNames)
+		if err != nil {
+			logger.Errorw("could not evaluate historical regressions", "error", err)
+		}
+	}
+	publishAll(ctx, logger, sinks, record)
+	return regressed
+}
+
 func tryConnect(ctx context.Context, logger logging.Logger) (*client.RobotClient, connectionStats, error) {
 	apiKey := os.Getenv("ESP32_CANARY_API_KEY")
 	apiKeyId := os.Getenv("ESP32_CANARY_API_KEY_ID")
@@ -126,42 +146,6 @@ func tryConnect(ctx context.Context, logger logging.Logger) (*client.RobotClient
 	return machine, stats, nil
 }
 
-func boardAPItest(ctx context.Context, pin board.GPIOPin) boardAPIStats {
-	stats := boardAPIStats{}
-	latencies := []float64{}
-	for range 20 {
-		time.Sleep(500 * time.Millisecond)
-		_, err := pin.Get(ctx, nil)
-		if err != nil {
-			stats.failures += 1
-			stats.connectionError = err.Error()
-			continue
-		}
-		startTime := time.Now()
-		err = pin.Set(ctx, true, nil)
-		if err != nil {
-			stats.failures += 1
-			stats.connectionError = err.Error()
-			continue
-		}
-		latencies = append(latencies, (float64(time.Since(startTime).Milliseconds())))
-		value, err := pin.Get(ctx, nil)
-		if err != nil {
-			stats.failures += 1
-			stats.connectionError = err.Error()
-			continue
-		}
-		if !value {
-			stats.failures += 1
-			stats.connectionError = "Pin not set to high successfully"
-			continue
-		}
-		stats.successes += 1
-	}
-	stats.avgLatencyMs, stats.avgLatencyMsStdDev = stat.MeanStdDev(latencies, nil)
-	return stats
-}
-
 func getVersion() (string, error) {
 	bi, ok := debug.ReadBuildInfo()
 	if !ok {
@@ -180,7 +164,21 @@ func getVersion() (string, error) {
 	return sdk_version, nil
 }
 
-func buildRecord(runTimestamp time.Time, connStats connectionStats, boardStats boardAPIStats) (bson.M, error) {
+func apiStatsToBSON(stats APIStats) bson.M {
+	return bson.M{
+		"successes":        stats.successes,
+		"failures":         stats.failures,
+		"avg_latency_ms":   stats.avgLatencyMs,
+		"p50_latency_ms":   stats.p50LatencyMs,
+		"p90_latency_ms":   stats.p90LatencyMs,
+		"p95_latency_ms":   stats.p95LatencyMs,
+		"p99_latency_ms":   stats.p99LatencyMs,
+		"max_latency_ms":   stats.maxLatencyMs,
+		"connection_error": stats.connectionError,
+	}
+}
+
+func buildRecord(runTimestamp time.Time, connStats connectionStats, componentStats map[string]APIStats) (bson.M, error) {
 	sdkVersion, err := getVersion()
 	if err != nil {
 		return nil, err
@@ -189,21 +187,29 @@ func buildRecord(runTimestamp time.Time, connStats connectionStats, boardStats b
 	connectionErr := ""
 	if !connStats.connectionSuccess {
 		connectionErr = connStats.connectionError
-	} else if boardStats.failures > 0 {
-		connectionErr = boardStats.connectionError
+	} else {
+		for _, stats := range componentStats {
+			if stats.failures > 0 {
+				connectionErr = stats.connectionError
+				break
+			}
+		}
+	}
+
+	components := bson.M{}
+	for name, stats := range componentStats {
+		components[name] = apiStatsToBSON(stats)
 	}
 
 	return bson.M{
-		"timestamp":                    runTimestamp,
-		"sdk_type":                     "Go",
-		"sdk_version":                  sdkVersion,
-		"connection_success":           connStats.connectionSuccess,
-		"connection_error":             connectionErr,
-		"connection_latency_ms":        connStats.connectionLatencyMs,
-		"connection_attempts":          connStats.connectionAttempts,
-		"board_api_successes":          boardStats.successes,
-		"board_api_failures":           boardStats.failures,
-		"board_api_avg_latency_ms":     boardStats.avgLatencyMs,
-		"board_api_latency_ms_std_dev": boardStats.avgLatencyMsStdDev,
+		"timestamp":             runTimestamp,
+		"schema_version":        migrations.CurrentSchemaVersion,
+		"sdk_type":              "Go",
+		"sdk_version":           sdkVersion,
+		"connection_success":    connStats.connectionSuccess,
+		"connection_error":      connectionErr,
+		"connection_latency_ms": connStats.connectionLatencyMs,
+		"connection_attempts":   connStats.connectionAttempts,
+		"components":            components,
 	}, nil
 }
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.viam.com/rdk/components/board"
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/components/encoder"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/components/sensor"
+	"go.viam.com/rdk/components/servo"
+	"go.viam.com/rdk/robot/client"
+
+	"go.opentelemetry.io/otel"
+
+	"go.viam.com/micro-rdk/canary/tdigest"
+)
+
+// tracer emits one span per exercised API call when an OTLP exporter has
+// been configured (see newOTLPSink); it's a harmless no-op otherwise.
+var tracer = otel.Tracer("micrordk_canary")
+
+// tdigestCompression trades off centroid count against quantile accuracy;
+// ~100 gives ~1% relative error on tail quantiles, with a few hundred
+// centroids once thousands of samples have been added.
+const tdigestCompression = 100
+
+const defaultIterations = 20
+
+// hasMethod reports whether name is present in methods, or true if methods
+// is empty (meaning "exercise everything").
+func hasMethod(methods []string, name string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, m := range methods {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// iterationsOrDefault returns n if positive, otherwise defaultIterations.
+func iterationsOrDefault(n int) int {
+	if n <= 0 {
+		return defaultIterations
+	}
+	return n
+}
+
+// runTimedLoop runs attempt iterations times, sleeping between each to avoid
+// hammering the component, and feeds each successful latency into a
+// streaming t-digest rather than retaining every sample, so memory stays
+// O(compression) even as iterations scale into the thousands. Each iteration
+// is wrapped in a trace span named after op.
+func runTimedLoop(ctx context.Context, op string, iterations int, stats *APIStats, attempt func() (time.Duration, error)) {
+	digest := tdigest.New(tdigestCompression)
+	sum := 0.0
+	for range iterations {
+		time.Sleep(500 * time.Millisecond)
+		_, span := tracer.Start(ctx, op)
+		d, err := attempt()
+		span.End()
+		if err != nil {
+			stats.failures++
+			stats.connectionError = err.Error()
+			continue
+		}
+		stats.successes++
+		latencyMs := float64(d.Milliseconds())
+		digest.Add(latencyMs)
+		sum += latencyMs
+	}
+	if digest.Count() > 0 {
+		stats.avgLatencyMs = sum / float64(digest.Count())
+	}
+	stats.p50LatencyMs = digest.Quantile(0.50)
+	stats.p90LatencyMs = digest.Quantile(0.90)
+	stats.p95LatencyMs = digest.Quantile(0.95)
+	stats.p99LatencyMs = digest.Quantile(0.99)
+	stats.maxLatencyMs = digest.Max()
+}
+
+// --- board ---
+
+type boardTest struct {
+	name       string
+	pinName    string
+	methods    []string
+	iterations int
+}
+
+func newBoardTest(cfg ComponentConfig) ComponentTest {
+	return &boardTest{
+		name:       cfg.ComponentName,
+		pinName:    "32",
+		methods:    cfg.MethodsToExercise,
+		iterations: iterationsOrDefault(cfg.Iterations),
+	}
+}
+
+func (t *boardTest) Name() string { return t.name }
+
+func (t *boardTest) Run(ctx context.Context, machine *client.RobotClient) APIStats {
+	stats := APIStats{}
+	b, err := board.FromRobot(machine, t.name)
+	if err != nil {
+		stats.failures = t.iterations
+		stats.connectionError = err.Error()
+		return stats
+	}
+	pin, err := b.GPIOPinByName(t.pinName)
+	if err != nil {
+		stats.failures = t.iterations
+		stats.connectionError = err.Error()
+		return stats
+	}
+	runTimedLoop(ctx, "board.Set", t.iterations, &stats, func() (time.Duration, error) {
+		if hasMethod(t.methods, "Get") {
+			if _, err := pin.Get(ctx, nil); err != nil {
+				return 0, err
+			}
+		}
+		start := time.Now()
+		if hasMethod(t.methods, "Set") {
+			if err := pin.Set(ctx, true, nil); err != nil {
+				return 0, err
+			}
+		}
+		elapsed := time.Since(start)
+		value, err := pin.Get(ctx, nil)
+		if err != nil {
+			return 0, err
+		}
+		if hasMethod(t.methods, "Set") && !value {
+			return 0, errors.New("pin not set to high successfully")
+		}
+		return elapsed, nil
+	})
+	return stats
+}
+
+// --- motor ---
+
+type motorTest struct {
+	name       string
+	methods    []string
+	iterations int
+}
+
+func newMotorTest(cfg ComponentConfig) ComponentTest {
+	return &motorTest{name: cfg.ComponentName, methods: cfg.MethodsToExercise, iterations: iterationsOrDefault(cfg.Iterations)}
+}
+
+func (t *motorTest) Name() string { return t.name }
+
+func (t *motorTest) Run(ctx context.Context, machine *client.RobotClient) APIStats {
+	stats := APIStats{}
+	m, err := motor.FromRobot(machine, t.name)
+	if err != nil {
+		stats.failures = t.iterations
+		stats.connectionError = err.Error()
+		return stats
+	}
+	runTimedLoop(ctx, "motor.SetPower", t.iterations, &stats, func() (time.Duration, error) {
+		start := time.Now()
+		if hasMethod(t.methods, "SetPower") {
+			if err := m.SetPower(ctx, 0.5, nil); err != nil {
+				return 0, err
+			}
+		}
+		if hasMethod(t.methods, "IsPowered") {
+			if _, _, err := m.IsPowered(ctx, nil); err != nil {
+				return 0, err
+			}
+		}
+		elapsed := time.Since(start)
+		if hasMethod(t.methods, "Stop") {
+			if err := m.Stop(ctx, nil); err != nil {
+				return 0, err
+			}
+		}
+		return elapsed, nil
+	})
+	return stats
+}
+
+// --- servo ---
+
+type servoTest struct {
+	name       string
+	methods    []string
+	iterations int
+}
+
+func newServoTest(cfg ComponentConfig) ComponentTest {
+	return &servoTest{name: cfg.ComponentName, methods: cfg.MethodsToExercise, iterations: iterationsOrDefault(cfg.Iterations)}
+}
+
+func (t *servoTest) Name() string { return t.name }
+
+func (t *servoTest) Run(ctx context.Context, machine *client.RobotClient) APIStats {
+	stats := APIStats{}
+	s, err := servo.FromRobot(machine, t.name)
+	if err != nil {
+		stats.failures = t.iterations
+		stats.connectionError = err.Error()
+		return stats
+	}
+	runTimedLoop(ctx, "servo.Move", t.iterations, &stats, func() (time.Duration, error) {
+		start := time.Now()
+		if hasMethod(t.methods, "Move") {
+			if err := s.Move(ctx, 90, nil); err != nil {
+				return 0, err
+			}
+		}
+		if hasMethod(t.methods, "Position") {
+			if _, err := s.Position(ctx, nil); err != nil {
+				return 0, err
+			}
+		}
+		return time.Since(start), nil
+	})
+	return stats
+}
+
+// --- sensor ---
+
+type sensorTest struct {
+	name       string
+	methods    []string
+	iterations int
+}
+
+func newSensorTest(cfg ComponentConfig) ComponentTest {
+	return &sensorTest{name: cfg.ComponentName, methods: cfg.MethodsToExercise, iterations: iterationsOrDefault(cfg.Iterations)}
+}
+
+func (t *sensorTest) Name() string { return t.name }
+
+func (t *sensorTest) Run(ctx context.Context, machine *client.RobotClient) APIStats {
+	stats := APIStats{}
+	se, err := sensor.FromRobot(machine, t.name)
+	if err != nil {
+		stats.failures = t.iterations
+		stats.connectionError = err.Error()
+		return stats
+	}
+	runTimedLoop(ctx, "sensor.Readings", t.iterations, &stats, func() (time.Duration, error) {
+		start := time.Now()
+		if _, err := se.Readings(ctx, nil); err != nil {
+			return 0, err
+		}
+		return time.Since(start), nil
+	})
+	return stats
+}
+
+// --- encoder ---
+
+type encoderTest struct {
+	name       string
+	methods    []string
+	iterations int
+}
+
+func newEncoderTest(cfg ComponentConfig) ComponentTest {
+	return &encoderTest{name: cfg.ComponentName, methods: cfg.MethodsToExercise, iterations: iterationsOrDefault(cfg.Iterations)}
+}
+
+func (t *encoderTest) Name() string { return t.name }
+
+func (t *encoderTest) Run(ctx context.Context, machine *client.RobotClient) APIStats {
+	stats := APIStats{}
+	e, err := encoder.FromRobot(machine, t.name)
+	if err != nil {
+		stats.failures = t.iterations
+		stats.connectionError = err.Error()
+		return stats
+	}
+	runTimedLoop(ctx, "encoder.Position", t.iterations, &stats, func() (time.Duration, error) {
+		start := time.Now()
+		if _, _, err := e.Position(ctx, encoder.PositionTypeUnspecified, nil); err != nil {
+			return 0, err
+		}
+		return time.Since(start), nil
+	})
+	return stats
+}
+
+// --- camera ---
+
+type cameraTest struct {
+	name       string
+	methods    []string
+	iterations int
+}
+
+func newCameraTest(cfg ComponentConfig) ComponentTest {
+	return &cameraTest{name: cfg.ComponentName, methods: cfg.MethodsToExercise, iterations: iterationsOrDefault(cfg.Iterations)}
+}
+
+func (t *cameraTest) Name() string { return t.name }
+
+func (t *cameraTest) Run(ctx context.Context, machine *client.RobotClient) APIStats {
+	stats := APIStats{}
+	c, err := camera.FromRobot(machine, t.name)
+	if err != nil {
+		stats.failures = t.iterations
+		stats.connectionError = err.Error()
+		return stats
+	}
+	runTimedLoop(ctx, "camera.Image", t.iterations, &stats, func() (time.Duration, error) {
+		start := time.Now()
+		if _, _, err := c.Image(ctx, "", nil); err != nil {
+			return 0, err
+		}
+		return time.Since(start), nil
+	})
+	return stats
+}
+
+// --- movement_sensor ---
+
+type movementSensorTest struct {
+	name       string
+	methods    []string
+	iterations int
+}
+
+func newMovementSensorTest(cfg ComponentConfig) ComponentTest {
+	return &movementSensorTest{name: cfg.ComponentName, methods: cfg.MethodsToExercise, iterations: iterationsOrDefault(cfg.Iterations)}
+}
+
+func (t *movementSensorTest) Name() string { return t.name }
+
+func (t *movementSensorTest) Run(ctx context.Context, machine *client.RobotClient) APIStats {
+	stats := APIStats{}
+	ms, err := movementsensor.FromRobot(machine, t.name)
+	if err != nil {
+		stats.failures = t.iterations
+		stats.connectionError = err.Error()
+		return stats
+	}
+	runTimedLoop(ctx, "movement_sensor.Position", t.iterations, &stats, func() (time.Duration, error) {
+		start := time.Now()
+		if hasMethod(t.methods, "Position") {
+			if _, _, err := ms.Position(ctx, nil); err != nil {
+				return 0, err
+			}
+		}
+		if hasMethod(t.methods, "LinearVelocity") {
+			if _, err := ms.LinearVelocity(ctx, nil); err != nil {
+				return 0, err
+			}
+		}
+		return time.Since(start), nil
+	})
+	return stats
+}
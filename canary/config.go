@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComponentConfig describes a single component under test: which RDK resource
+// to look up, what type of ComponentTest to build for it, which methods on
+// that component's API to exercise, and how many iterations to run.
+type ComponentConfig struct {
+	ComponentName     string   `yaml:"component_name" json:"component_name"`
+	Type              string   `yaml:"type" json:"type"`
+	MethodsToExercise []string `yaml:"methods_to_exercise" json:"methods_to_exercise"`
+	Iterations        int      `yaml:"iterations" json:"iterations"`
+}
+
+// CanaryConfig is the top-level shape of the canary's YAML/JSON config file.
+type CanaryConfig struct {
+	Components []ComponentConfig `yaml:"components" json:"components"`
+}
+
+// loadConfig reads a CanaryConfig from path, choosing a YAML or JSON decoder
+// based on the file extension.
+func loadConfig(path string) (CanaryConfig, error) {
+	var cfg CanaryConfig
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read canary config %q: %w", path, err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(raw, &cfg); err != nil {
+			return cfg, fmt.Errorf("failed to parse canary config %q: %w", path, err)
+		}
+	case ".json":
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return cfg, fmt.Errorf("failed to parse canary config %q: %w", path, err)
+		}
+	default:
+		return cfg, fmt.Errorf("unsupported canary config extension %q", ext)
+	}
+
+	return cfg, nil
+}
+
+// filterComponents restricts cfg to the named components, preserving order.
+// An empty names set is a no-op.
+func filterComponents(cfg CanaryConfig, names []string) CanaryConfig {
+	if len(names) == 0 {
+		return cfg
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, n := range names {
+		wanted[strings.TrimSpace(n)] = true
+	}
+	filtered := CanaryConfig{}
+	for _, c := range cfg.Components {
+		if wanted[c.ComponentName] {
+			filtered.Components = append(filtered.Components, c)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,123 @@
+// Package migrations tracks and applies schema changes to the canary result
+// database in an idempotent, concurrency-safe way, so the shape of
+// raw_results can evolve (new indexes, renamed/restructured fields) without
+// making older records unqueryable or requiring a coordinated rollout.
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CurrentSchemaVersion is the schema_version stamped onto every record
+// built by the canary. Bump it, and add a corresponding Migration to all,
+// whenever a migration renames or restructures raw_results fields.
+const CurrentSchemaVersion = 1
+
+// migrationsCollection records one document per applied migration, keyed by
+// its Version, so Run can tell which migrations still need to apply.
+const migrationsCollection = "migrations"
+
+// resultsCollection is the canary result collection migrations operate on.
+const resultsCollection = "raw_results"
+
+// Migration is a single, ordered step in the evolution of the raw_results
+// schema. Up must be idempotent: multiple canary runners can start at once
+// and race to apply the same version, and Up may run more than once for the
+// same version if a runner crashes between applying it and recording it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+}
+
+// all lists every migration in order. Append to this slice; never reorder,
+// renumber, or remove an entry once it has shipped, since Version is the
+// durable record of what has already run against production data.
+var all = []Migration{
+	{
+		Version: 1,
+		Name:    "index timestamp and sdk_version+timestamp",
+		Up:      createIndexes,
+	},
+	{
+		Version: 2,
+		Name:    "backfill schema_version on existing docs",
+		Up:      backfillSchemaVersion,
+	},
+}
+
+// Run applies every migration in all that isn't yet recorded as applied, in
+// version order. It's safe to call from multiple canary runners starting at
+// once: Up steps are idempotent, and a migration's completion is recorded
+// with an insert keyed on its Version, so a runner that loses the race to
+// record first just treats the resulting duplicate-key error as "someone
+// else already applied this one".
+func Run(ctx context.Context, db *mongo.Database) error {
+	coll := db.Collection(migrationsCollection)
+	for _, m := range all {
+		applied, err := isApplied(ctx, coll, m.Version)
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if applied {
+			continue
+		}
+		if err := m.Up(ctx, db); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if err := recordApplied(ctx, coll, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+func isApplied(ctx context.Context, coll *mongo.Collection, version int) (bool, error) {
+	err := coll.FindOne(ctx, bson.M{"_id": version}).Err()
+	switch err {
+	case nil:
+		return true, nil
+	case mongo.ErrNoDocuments:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// recordApplied atomically claims version as applied. A duplicate-key error
+// means a concurrent runner recorded it first; that's the expected outcome
+// of two runners racing to apply the same migration, not a failure.
+func recordApplied(ctx context.Context, coll *mongo.Collection, m Migration) error {
+	_, err := coll.InsertOne(ctx, bson.M{"_id": m.Version, "name": m.Name})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// createIndexes adds the indexes the regression detector relies on to scan
+// recent runs without a collection scan: newest-first by timestamp, and
+// newest-first by timestamp within a given sdk_version.
+func createIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(resultsCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "sdk_version", Value: 1}, {Key: "timestamp", Value: -1}}},
+	})
+	return err
+}
+
+// backfillSchemaVersion stamps schema_version 0 onto every raw_results doc
+// that predates it, so later migrations and readers can distinguish
+// pre-migration documents from ones written against a known schema version
+// without an expensive per-field shape probe.
+func backfillSchemaVersion(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(resultsCollection).UpdateMany(ctx,
+		bson.M{"schema_version": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"schema_version": 0}},
+	)
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.viam.com/rdk/robot/client"
+)
+
+// APIStats is the generic result of exercising a single component's API for
+// some number of iterations. It replaces the board-specific boardAPIStats so
+// every component type can report through the same shape. Latency is
+// summarized by quantile rather than mean/stddev so a single slow call among
+// many fast ones isn't averaged away.
+type APIStats struct {
+	successes       int
+	failures        int
+	avgLatencyMs    float64
+	p50LatencyMs    float64
+	p90LatencyMs    float64
+	p95LatencyMs    float64
+	p99LatencyMs    float64
+	maxLatencyMs    float64
+	connectionError string
+}
+
+// ComponentTest exercises a single configured component's API surface and
+// reports timing/success statistics. Implementations are built from a
+// ComponentConfig by a componentTestFactory registered in
+// componentTestRegistry.
+type ComponentTest interface {
+	// Name returns the configured component name, used as the key under
+	// which this test's APIStats are nested in the result record.
+	Name() string
+	// Run exercises the component against machine and returns the
+	// resulting stats. It must not panic on a component lookup or method
+	// call failure; such failures are reported through APIStats instead.
+	Run(ctx context.Context, machine *client.RobotClient) APIStats
+}
+
+// componentTestFactory builds a ComponentTest from its YAML/JSON configuration.
+type componentTestFactory func(cfg ComponentConfig) ComponentTest
+
+// componentTestRegistry maps a ComponentConfig.Type to the factory that
+// builds the ComponentTest for it. Add an entry here when a new component
+// type gains canary coverage.
+var componentTestRegistry = map[string]componentTestFactory{
+	"board":           newBoardTest,
+	"motor":           newMotorTest,
+	"servo":           newServoTest,
+	"sensor":          newSensorTest,
+	"encoder":         newEncoderTest,
+	"camera":          newCameraTest,
+	"movement_sensor": newMovementSensorTest,
+}
+
+// buildComponentTests constructs one ComponentTest per entry in cfg.Components,
+// returning an error if any entry names a type with no registered factory.
+func buildComponentTests(cfg CanaryConfig) ([]ComponentTest, error) {
+	tests := make([]ComponentTest, 0, len(cfg.Components))
+	for _, c := range cfg.Components {
+		factory, ok := componentTestRegistry[c.Type]
+		if !ok {
+			return nil, fmt.Errorf("no canary test registered for component type %q (component %q)", c.Type, c.ComponentName)
+		}
+		tests = append(tests, factory(c))
+	}
+	return tests, nil
+}
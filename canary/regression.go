@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// regressionHistoryWindow is how many recent runs of a given sdk_version
+	// are pulled from raw_results to build each comparison sample.
+	regressionHistoryWindow = 20
+	// regressionZThreshold corresponds to p < 0.01 on a two-sided normal
+	// approximation of the Mann-Whitney U statistic.
+	regressionZThreshold = 2.58
+	// regressionPctThreshold is the minimum practical latency degradation
+	// (current vs. prior mean) required on top of statistical significance.
+	regressionPctThreshold = 0.15
+	// regressionPValueThreshold is the significance level for Fisher's exact
+	// test on connection success/failure counts.
+	regressionPValueThreshold = 0.01
+	// regressionMinCurrentSamples is the fewest current-sdk_version samples
+	// (including this run, via latencyRegressionFor/connectionSuccessRegressionFor
+	// folding it in) required before either test is even attempted. Below
+	// this, neither test has enough statistical power to ever reach its
+	// threshold regardless of how bad the regression is: with
+	// regressionHistoryWindow=20 prior samples, reaching regressionZThreshold
+	// needs at least 3 current samples in the best case, and Fisher's exact
+	// test on a single current sample can't beat regressionPValueThreshold
+	// even for a 100%-failure vs. 100%-success contrast. So a newly-bumped
+	// sdk_version still needs a handful of runs before it's gated, not just
+	// one.
+	regressionMinCurrentSamples = 5
+)
+
+// latencyRegression summarizes a Mann-Whitney U comparison of one latency
+// metric's recent history between the current sdk_version and the
+// immediately preceding one.
+type latencyRegression struct {
+	Metric         string  `bson:"metric"`
+	Detected       bool    `bson:"detected"`
+	ZScore         float64 `bson:"z_score"`
+	CurrentMean    float64 `bson:"current_mean_ms"`
+	PriorMean      float64 `bson:"prior_mean_ms"`
+	CurrentVersion string  `bson:"current_version"`
+	PriorVersion   string  `bson:"prior_version"`
+}
+
+// connectionRegression summarizes a Fisher's exact test comparison of
+// connection success rate between the current sdk_version and the
+// immediately preceding one.
+type connectionRegression struct {
+	Detected       bool    `bson:"detected"`
+	PValue         float64 `bson:"p_value"`
+	CurrentRate    float64 `bson:"current_success_rate"`
+	PriorRate      float64 `bson:"prior_success_rate"`
+	CurrentVersion string  `bson:"current_version"`
+	PriorVersion   string  `bson:"prior_version"`
+}
+
+// annotateRegressions queries coll for the recent history of sdkVersion and
+// the version before it, runs latency and connection-success regression
+// tests, and adds the results to record as "regression_detected" and
+// "regression_details". It returns whether any regression was flagged.
+func annotateRegressions(ctx context.Context, coll *mongo.Collection, record bson.M, sdkVersion string, componentNames []string) (bool, error) {
+	priorVersion, err := previousVersion(ctx, coll, sdkVersion)
+	if err != nil {
+		return false, err
+	}
+	if priorVersion == "" {
+		// Nothing to compare against yet (e.g. first run of this canary).
+		record["regression_detected"] = false
+		return false, nil
+	}
+
+	anyDetected := false
+
+	fields := append([]string{"connection_latency_ms"}, componentLatencyFields(componentNames)...)
+	latencyResults := make([]latencyRegression, 0, len(fields))
+	for _, field := range fields {
+		result, ok, err := latencyRegressionFor(ctx, coll, record, sdkVersion, priorVersion, field)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			continue
+		}
+		latencyResults = append(latencyResults, result)
+		anyDetected = anyDetected || result.Detected
+	}
+
+	connResult, ok, err := connectionSuccessRegressionFor(ctx, coll, record, sdkVersion, priorVersion)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		anyDetected = anyDetected || connResult.Detected
+	}
+
+	record["regression_detected"] = anyDetected
+	record["regression_details"] = bson.M{
+		"latency":            latencyResults,
+		"connection_success": connResult,
+	}
+
+	return anyDetected, nil
+}
+
+func componentLatencyFields(componentNames []string) []string {
+	fields := make([]string, 0, len(componentNames))
+	for _, name := range componentNames {
+		fields = append(fields, "components."+name+".avg_latency_ms")
+	}
+	return fields
+}
+
+// previousVersion returns the most recent sdk_version in raw_results that
+// differs from sdkVersion, scanning runs newest-first.
+func previousVersion(ctx context.Context, coll *mongo.Collection, sdkVersion string) (string, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetLimit(int64(10 * regressionHistoryWindow)).
+		SetProjection(bson.M{"sdk_version": 1})
+	cursor, err := coll.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return "", err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			SDKVersion string `bson:"sdk_version"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return "", err
+		}
+		if doc.SDKVersion != "" && doc.SDKVersion != sdkVersion {
+			return doc.SDKVersion, nil
+		}
+	}
+	return "", cursor.Err()
+}
+
+// latencyRegressionFor compares the recent history of field between
+// sdkVersion and priorVersion, including record's own value (this run hasn't
+// been inserted into coll yet) in the current-version sample so a
+// newly-bumped sdkVersion starts accumulating comparisons from its first run
+// rather than waiting for a full regressionHistoryWindow of history. ok is
+// false when prior has no samples, or current has fewer than
+// regressionMinCurrentSamples (see its doc comment for why gating on fewer
+// would have no statistical power).
+func latencyRegressionFor(ctx context.Context, coll *mongo.Collection, record bson.M, sdkVersion, priorVersion, field string) (latencyRegression, bool, error) {
+	current, err := fetchLatencySamples(ctx, coll, sdkVersion, field, regressionHistoryWindow)
+	if err != nil {
+		return latencyRegression{}, false, err
+	}
+	if v, ok := lookupDotted(record, field); ok {
+		current = append(current, v)
+	}
+	prior, err := fetchLatencySamples(ctx, coll, priorVersion, field, regressionHistoryWindow)
+	if err != nil {
+		return latencyRegression{}, false, err
+	}
+	if len(current) < regressionMinCurrentSamples || len(prior) == 0 {
+		return latencyRegression{}, false, nil
+	}
+
+	z := mannWhitneyZ(current, prior)
+	currentMean := mean(current)
+	priorMean := mean(prior)
+	pctWorse := (currentMean - priorMean) / priorMean
+
+	return latencyRegression{
+		Metric:         field,
+		Detected:       math.Abs(z) > regressionZThreshold && pctWorse > regressionPctThreshold,
+		ZScore:         z,
+		CurrentMean:    currentMean,
+		PriorMean:      priorMean,
+		CurrentVersion: sdkVersion,
+		PriorVersion:   priorVersion,
+	}, true, nil
+}
+
+// connectionSuccessRegressionFor compares connection success rate between
+// sdkVersion and priorVersion via a two-sided Fisher's exact test on the 2x2
+// success/failure contingency table, counting record's own result (this run
+// hasn't been inserted into coll yet) alongside the current version's
+// history so a newly-bumped sdkVersion starts accumulating comparisons from
+// its first run. ok is false when priorTotal is zero, or curTotal is below
+// regressionMinCurrentSamples (see its doc comment for why gating on fewer
+// would have no statistical power).
+func connectionSuccessRegressionFor(ctx context.Context, coll *mongo.Collection, record bson.M, sdkVersion, priorVersion string) (connectionRegression, bool, error) {
+	curSuccesses, curTotal, err := successCounts(ctx, coll, sdkVersion, regressionHistoryWindow)
+	if err != nil {
+		return connectionRegression{}, false, err
+	}
+	if success, ok := record["connection_success"].(bool); ok {
+		curTotal++
+		if success {
+			curSuccesses++
+		}
+	}
+	priorSuccesses, priorTotal, err := successCounts(ctx, coll, priorVersion, regressionHistoryWindow)
+	if err != nil {
+		return connectionRegression{}, false, err
+	}
+	if curTotal < regressionMinCurrentSamples || priorTotal == 0 {
+		return connectionRegression{}, false, nil
+	}
+
+	a, b := curSuccesses, curTotal-curSuccesses
+	c, d := priorSuccesses, priorTotal-priorSuccesses
+	p := fishersExactTwoSided(a, b, c, d)
+	curRate := float64(curSuccesses) / float64(curTotal)
+	priorRate := float64(priorSuccesses) / float64(priorTotal)
+
+	return connectionRegression{
+		Detected:       p < regressionPValueThreshold && curRate < priorRate,
+		PValue:         p,
+		CurrentRate:    curRate,
+		PriorRate:      priorRate,
+		CurrentVersion: sdkVersion,
+		PriorVersion:   priorVersion,
+	}, true, nil
+}
+
+// fetchLatencySamples returns the values of the (possibly dotted) field from
+// the n most recent raw_results docs matching sdk_version, newest runs first.
+// Docs where the field is missing or not numeric are skipped, so fewer than n
+// values can come back.
+func fetchLatencySamples(ctx context.Context, coll *mongo.Collection, sdkVersion, field string, n int) ([]float64, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetLimit(int64(n)).
+		SetProjection(bson.M{field: 1})
+	cursor, err := coll.Find(ctx, bson.M{"sdk_version": sdkVersion}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var samples []float64
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		if v, ok := lookupDotted(doc, field); ok {
+			samples = append(samples, v)
+		}
+	}
+	return samples, cursor.Err()
+}
+
+// successCounts returns the number of successful connections and the total
+// number of runs among the n most recent raw_results docs for sdkVersion.
+func successCounts(ctx context.Context, coll *mongo.Collection, sdkVersion string, n int) (successes, total int, err error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetLimit(int64(n)).
+		SetProjection(bson.M{"connection_success": 1})
+	cursor, err := coll.Find(ctx, bson.M{"sdk_version": sdkVersion}, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ConnectionSuccess bool `bson:"connection_success"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return 0, 0, err
+		}
+		total++
+		if doc.ConnectionSuccess {
+			successes++
+		}
+	}
+	return successes, total, cursor.Err()
+}
+
+// lookupDotted resolves a dotted field path (e.g.
+// "components.board.avg_latency_ms") against a decoded BSON document and
+// returns its value as a float64.
+func lookupDotted(doc bson.M, path string) (float64, bool) {
+	var cur interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(bson.M)
+		if !ok {
+			return 0, false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return 0, false
+		}
+	}
+	switch v := cur.(type) {
+	case float64:
+		return v, true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func mean(xs []float64) float64 {
+	sum := 0.0
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// mannWhitneyZ computes the normal-approximation z-score for a Mann-Whitney
+// U test between two samples. The approximation is accurate for n1, n2 of
+// about 20 or more; it is applied at every sample size, and callers pass up
+// to regressionHistoryWindow+1 current samples but as few as
+// regressionMinCurrentSamples current and one prior sample.
+func mannWhitneyZ(sample1, sample2 []float64) float64 {
+	n1, n2 := len(sample1), len(sample2)
+
+	type labeled struct {
+		value float64
+		group int
+	}
+	combined := make([]labeled, 0, n1+n2)
+	for _, v := range sample1 {
+		combined = append(combined, labeled{v, 1})
+	}
+	for _, v := range sample2 {
+		combined = append(combined, labeled{v, 2})
+	}
+	sort.Slice(combined, func(i, j int) bool { return combined[i].value < combined[j].value })
+
+	ranks := make([]float64, len(combined))
+	for i := 0; i < len(combined); {
+		j := i
+		for j < len(combined) && combined[j].value == combined[i].value {
+			j++
+		}
+		avgRank := float64(i+j+1) / 2 // average rank for ties, 1-indexed
+		for k := i; k < j; k++ {
+			ranks[k] = avgRank
+		}
+		i = j
+	}
+
+	r1 := 0.0
+	for i, l := range combined {
+		if l.group == 1 {
+			r1 += ranks[i]
+		}
+	}
+
+	u := r1 - float64(n1*(n1+1))/2
+	meanU := float64(n1*n2) / 2
+	stdDevU := math.Sqrt(float64(n1*n2*(n1+n2+1)) / 12)
+	if stdDevU == 0 {
+		return 0
+	}
+	return (u - meanU) / stdDevU
+}
+
+// fishersExactTwoSided computes the two-sided p-value for the 2x2
+// contingency table [[a, b], [c, d]] by summing the hypergeometric
+// probability of every table with the same margins that is at least as
+// extreme as the observed one.
+func fishersExactTwoSided(a, b, c, d int) float64 {
+	n := a + b + c + d
+	rowA := a + b
+	colA := a + c
+
+	logP := func(a int) float64 {
+		b := rowA - a
+		c := colA - a
+		d := n - rowA - c
+		if b < 0 || c < 0 || d < 0 {
+			return math.Inf(-1)
+		}
+		return logChoose(rowA, a) + logChoose(n-rowA, c) - logChoose(n, colA)
+	}
+
+	pObserved := math.Exp(logP(a))
+
+	lo := max(0, colA-(n-rowA))
+	hi := min(rowA, colA)
+	total := 0.0
+	for x := lo; x <= hi; x++ {
+		p := math.Exp(logP(x))
+		if p <= pObserved*(1+1e-9) {
+			total += p
+		}
+	}
+	return total
+}
+
+func logChoose(n, k int) float64 {
+	if k < 0 || k > n {
+		return math.Inf(-1)
+	}
+	nFact, _ := math.Lgamma(float64(n + 1))
+	kFact, _ := math.Lgamma(float64(k + 1))
+	nkFact, _ := math.Lgamma(float64(n - k + 1))
+	return nFact - kFact - nkFact
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestMannWhitneyZ checks mannWhitneyZ against hand-computed z-scores for
+// small samples simple enough to verify by computing the rank sum directly.
+func TestMannWhitneyZ(t *testing.T) {
+	cases := []struct {
+		name    string
+		sample1 []float64
+		sample2 []float64
+		wantZ   float64
+	}{
+		{
+			// Every value in sample1 is less than every value in sample2, so
+			// U = 0. meanU = 5*5/2 = 12.5, stdDevU = sqrt(5*5*11/12) ≈
+			// 4.7871, giving z = (0 - 12.5) / 4.7871 ≈ -2.6111.
+			name:    "fully separated samples",
+			sample1: []float64{1, 2, 3, 4, 5},
+			sample2: []float64{6, 7, 8, 9, 10},
+			wantZ:   -2.6111,
+		},
+		{
+			// Identical tied distributions: symmetric ranks give U = meanU,
+			// so z = 0.
+			name:    "identical distributions with ties",
+			sample1: []float64{1, 1, 2, 2},
+			sample2: []float64{1, 1, 2, 2},
+			wantZ:   0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			z := mannWhitneyZ(tc.sample1, tc.sample2)
+			if diff := math.Abs(z - tc.wantZ); diff > 0.001 {
+				t.Errorf("mannWhitneyZ() = %v, want %v (diff %v)", z, tc.wantZ, diff)
+			}
+		})
+	}
+}
+
+// TestFishersExactTwoSided checks fishersExactTwoSided against textbook
+// two-sided p-values for small contingency tables.
+func TestFishersExactTwoSided(t *testing.T) {
+	cases := []struct {
+		name       string
+		a, b, c, d int
+		wantP      float64
+	}{
+		{
+			// [[0,5],[5,0]]: the maximally-disjoint 5-vs-5 table. The only
+			// tables at least as extreme as the observed one are x=0 and its
+			// mirror x=5, each with probability 1/C(10,5) = 1/252, giving a
+			// two-sided p-value of 2/252 ≈ 0.007937.
+			name: "maximally disjoint",
+			a:    0, b: 5, c: 5, d: 0,
+			wantP: 2.0 / 252,
+		},
+		{
+			// Fisher's original lady-tasting-tea-style 4-vs-4 table, a
+			// standard worked example with two-sided p ≈ 0.4857.
+			name: "symmetric 4-vs-4 2x2 table",
+			a:    3, b: 1, c: 1, d: 3,
+			wantP: 0.4857,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := fishersExactTwoSided(tc.a, tc.b, tc.c, tc.d)
+			if diff := math.Abs(p - tc.wantP); diff > 0.001 {
+				t.Errorf("fishersExactTwoSided(%d,%d,%d,%d) = %v, want %v (diff %v)",
+					tc.a, tc.b, tc.c, tc.d, p, tc.wantP, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.viam.com/rdk/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"go.viam.com/micro-rdk/canary/migrations"
+)
+
+// ResultSink publishes a completed canary record somewhere a human or
+// dashboard can see it. Sinks are selected independently via CANARY_SINKS so
+// the canary keeps working (and reporting connection failures) even in
+// environments that only have some of Mongo, Prometheus, or OTLP available.
+type ResultSink interface {
+	Publish(ctx context.Context, record bson.M) error
+}
+
+// buildSinks constructs the ResultSinks named in the CANARY_SINKS env var
+// (comma-separated; defaults to "mongo" for backwards compatibility). It
+// also returns the Mongo collection backing the mongo sink, if selected and
+// reachable, since historical regression detection is Mongo-specific and
+// needs direct query access rather than going through ResultSink.Publish.
+// A sink that fails to initialize is logged and skipped rather than failing
+// the whole run.
+func buildSinks(ctx context.Context, logger logging.Logger) ([]ResultSink, *mongo.Collection) {
+	names := strings.Split(os.Getenv("CANARY_SINKS"), ",")
+	if os.Getenv("CANARY_SINKS") == "" {
+		names = []string{"mongo"}
+	}
+
+	var sinks []ResultSink
+	var mongoColl *mongo.Collection
+
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "mongo":
+			sink, err := newMongoSink(ctx)
+			if err != nil {
+				logger.Errorw("could not initialize mongo result sink", "error", err)
+				continue
+			}
+			sinks = append(sinks, sink)
+			mongoColl = sink.coll
+		case "prom":
+			sinks = append(sinks, newPrometheusSink())
+		case "otlp":
+			sink, err := newOTLPSink(ctx)
+			if err != nil {
+				logger.Errorw("could not initialize otlp result sink", "error", err)
+				continue
+			}
+			sinks = append(sinks, sink)
+		case "":
+			// Ignore empty entries from a stray leading/trailing/double comma.
+		default:
+			logger.Warnw("unrecognized canary result sink, skipping", "sink", name)
+		}
+	}
+
+	return sinks, mongoColl
+}
+
+// closeSinks closes every sink that has a Close method (the mongo sink's
+// connection, and the otlp sink's providers, which flush on close), logging
+// rather than failing on a close error.
+func closeSinks(ctx context.Context, logger logging.Logger, sinks []ResultSink) {
+	for _, sink := range sinks {
+		closer, ok := sink.(interface{ Close(context.Context) error })
+		if !ok {
+			continue
+		}
+		if err := closer.Close(ctx); err != nil {
+			logger.Errorw("error closing canary result sink", "error", err)
+		}
+	}
+}
+
+// publishAll attempts to publish record to every sink, continuing past
+// individual failures so one broken sink can't suppress telemetry (including
+// connection-failure telemetry) from the others.
+func publishAll(ctx context.Context, logger logging.Logger, sinks []ResultSink, record bson.M) {
+	for _, sink := range sinks {
+		if err := sink.Publish(ctx, record); err != nil {
+			logger.Errorw("result sink failed to publish canary record", "error", err)
+		}
+	}
+}
+
+// --- mongo ---
+
+// mongoSink is the original canary behavior: insert the record into
+// micrordk_canary.raw_results.
+type mongoSink struct {
+	client *mongo.Client
+	coll   *mongo.Collection
+}
+
+func newMongoSink(ctx context.Context) (*mongoSink, error) {
+	uri := os.Getenv("MONGODB_TEST_OUTPUT_URI")
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+	db := client.Database("micrordk_canary")
+	if err := migrations.Run(ctx, db); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("running canary schema migrations: %w", err)
+	}
+	coll := db.Collection("raw_results")
+	return &mongoSink{client: client, coll: coll}, nil
+}
+
+func (s *mongoSink) Publish(ctx context.Context, record bson.M) error {
+	_, err := s.coll.InsertOne(ctx, record)
+	return err
+}
+
+func (s *mongoSink) Close(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
+}
+
+// --- prometheus pushgateway ---
+
+// prometheusSink pushes a small set of gauges to a Prometheus pushgateway so
+// the canary can drive real-time dashboards without a Mongo endpoint.
+type prometheusSink struct {
+	pushgatewayURL string
+}
+
+func newPrometheusSink() *prometheusSink {
+	url := os.Getenv("CANARY_PUSHGATEWAY_URL")
+	if url == "" {
+		url = "http://localhost:9091"
+	}
+	return &prometheusSink{pushgatewayURL: url}
+}
+
+func (s *prometheusSink) Publish(ctx context.Context, record bson.M) error {
+	sdkVersion, _ := record["sdk_version"].(string)
+	labels := prometheus.Labels{"sdk_version": sdkVersion}
+
+	registry := prometheus.NewRegistry()
+
+	connLatency := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "micrordk_canary_connection_latency_ms",
+		Help:        "Latency of the most recent canary connection attempt, in milliseconds.",
+		ConstLabels: labels,
+	})
+	if v, ok := record["connection_latency_ms"].(float64); ok {
+		connLatency.Set(v)
+	}
+	registry.MustRegister(connLatency)
+
+	if components, ok := record["components"].(bson.M); ok {
+		if board, ok := components["board"].(bson.M); ok {
+			if v, ok := board["avg_latency_ms"].(float64); ok {
+				boardLatency := prometheus.NewGauge(prometheus.GaugeOpts{
+					Name:        "micrordk_canary_board_api_latency_ms",
+					Help:        "Average latency of the board GPIO API exercised by the canary, in milliseconds.",
+					ConstLabels: labels,
+				})
+				boardLatency.Set(v)
+				registry.MustRegister(boardLatency)
+			}
+		}
+	}
+
+	return push.New(s.pushgatewayURL, "micrordk_canary").Gatherer(registry).PushContext(ctx)
+}
+
+// --- otlp ---
+
+// otlpSink sends the same metrics as the Prometheus sink, plus a
+// per-component gauge, as OpenTelemetry metrics via OTLP. Per-iteration
+// trace spans (one per exercised API call, named after the op under test)
+// are emitted directly from the component test loop in components.go via the
+// package-level tracer, since that's where individual iterations happen;
+// Publish here only covers the aggregate record. newOTLPSink registers the
+// trace provider globally (via otel.SetTracerProvider) so that tracer picks
+// it up; Close flushes and shuts down both providers so the short-lived
+// canary process doesn't exit before its batched exports go out.
+type otlpSink struct {
+	meterProvider  *sdkmetric.MeterProvider
+	tracerProvider *sdktrace.TracerProvider
+	meter          otelmetric.Meter
+}
+
+func newOTLPSink(ctx context.Context) (*otlpSink, error) {
+	metricExporter, err := otlpmetricgrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)))
+
+	traceExporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExporter))
+	otel.SetTracerProvider(tracerProvider)
+
+	return &otlpSink{
+		meterProvider:  meterProvider,
+		tracerProvider: tracerProvider,
+		meter:          meterProvider.Meter("micrordk_canary"),
+	}, nil
+}
+
+// Close flushes and shuts down the meter and tracer providers so batched
+// metrics and spans are exported before the process exits, rather than
+// waiting for the PeriodicReader/BatchSpanProcessor's background interval.
+func (s *otlpSink) Close(ctx context.Context) error {
+	if err := s.tracerProvider.Shutdown(ctx); err != nil {
+		return err
+	}
+	return s.meterProvider.Shutdown(ctx)
+}
+
+func (s *otlpSink) Publish(ctx context.Context, record bson.M) error {
+	sdkVersion, _ := record["sdk_version"].(string)
+	sdkVersionAttr := attribute.String("sdk_version", sdkVersion)
+
+	connGauge, err := s.meter.Float64Gauge("micrordk_canary_connection_latency_ms")
+	if err != nil {
+		return err
+	}
+	if v, ok := record["connection_latency_ms"].(float64); ok {
+		connGauge.Record(ctx, v, otelmetric.WithAttributes(sdkVersionAttr))
+	}
+
+	components, ok := record["components"].(bson.M)
+	if !ok {
+		return nil
+	}
+	boardGauge, err := s.meter.Float64Gauge("micrordk_canary_board_api_latency_ms")
+	if err != nil {
+		return err
+	}
+	componentGauge, err := s.meter.Float64Gauge("micrordk_canary_component_api_latency_ms")
+	if err != nil {
+		return err
+	}
+	for name, raw := range components {
+		stats, ok := raw.(bson.M)
+		if !ok {
+			continue
+		}
+		latency, ok := stats["avg_latency_ms"].(float64)
+		if !ok {
+			continue
+		}
+		if name == "board" {
+			boardGauge.Record(ctx, latency, otelmetric.WithAttributes(sdkVersionAttr))
+		}
+		componentGauge.Record(ctx, latency, otelmetric.WithAttributes(
+			sdkVersionAttr,
+			attribute.String("component", name),
+		))
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+// Package tdigest implements a streaming t-digest for approximate quantile
+// tracking in O(compression) memory, regardless of how many samples are
+// added. This lets the canary report tail latency (p90/p95/p99) without
+// retaining every sample it has ever observed.
+//
+// See Dunning & Ertl, "Computing Extremely Accurate Quantiles Using
+// t-Digests" for the underlying algorithm.
+package tdigest
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// recompressFactor bounds how many centroids accumulate before a recluster
+// pass: one runs once the digest holds more than recompressFactor*compression
+// centroids.
+const recompressFactor = 20
+
+// centroid is a single weighted cluster of nearby samples.
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// TDigest is a streaming approximation of a distribution's quantiles.
+type TDigest struct {
+	compression float64
+	centroids   []centroid
+	totalWeight float64
+	max         float64
+	count       int
+}
+
+// New returns a TDigest targeting the given compression factor. A
+// compression of ~100 yields ~1% relative error on tail quantiles. The
+// centroid count is not held near compression: it grows slowly with the
+// number of samples (several hundred for a few thousand samples at
+// compression 100) and is capped at recompressFactor*compression.
+func New(compression float64) *TDigest {
+	return &TDigest{compression: compression}
+}
+
+// Add records a single sample.
+func (td *TDigest) Add(value float64) {
+	if td.count == 0 || value > td.max {
+		td.max = value
+	}
+	td.count++
+	td.insert(value, 1)
+	if float64(len(td.centroids)) > recompressFactor*td.compression {
+		td.recluster()
+	}
+}
+
+// Max returns the largest sample added so far.
+func (td *TDigest) Max() float64 {
+	return td.max
+}
+
+// Count returns the number of samples added so far.
+func (td *TDigest) Count() int {
+	return td.count
+}
+
+// insert merges (mean, weight) into the nearest existing centroid if that
+// keeps the centroid's weight under the size bound implied by its quantile
+// position, otherwise it inserts a new centroid in sorted order.
+func (td *TDigest) insert(mean, weight float64) {
+	if len(td.centroids) == 0 {
+		td.centroids = []centroid{{mean: mean, weight: weight}}
+		td.totalWeight = weight
+		return
+	}
+
+	nearest := 0
+	nearestDist := abs(td.centroids[0].mean - mean)
+	nearestCumulative := 0.0
+	cumulative := 0.0
+	for i, c := range td.centroids {
+		if dist := abs(c.mean - mean); dist < nearestDist {
+			nearest = i
+			nearestDist = dist
+			nearestCumulative = cumulative
+		}
+		cumulative += c.weight
+	}
+
+	newTotalWeight := td.totalWeight + weight
+	c := td.centroids[nearest]
+	q := (nearestCumulative + c.weight/2) / newTotalWeight
+	maxWeight := 4 * newTotalWeight * q * (1 - q) / td.compression
+
+	if c.weight+weight <= maxWeight {
+		td.centroids[nearest] = centroid{
+			mean:   (c.mean*c.weight + mean*weight) / (c.weight + weight),
+			weight: c.weight + weight,
+		}
+		td.totalWeight = newTotalWeight
+		return
+	}
+
+	idx := sort.Search(len(td.centroids), func(i int) bool {
+		return td.centroids[i].mean >= mean
+	})
+	td.centroids = append(td.centroids, centroid{})
+	copy(td.centroids[idx+1:], td.centroids[idx:])
+	td.centroids[idx] = centroid{mean: mean, weight: weight}
+	td.totalWeight = newTotalWeight
+}
+
+// recluster rebuilds the digest by re-inserting its current centroids, as
+// weighted samples, in a shuffled order. Centroids that now fit under the
+// size bound merge again; this lowers the count only modestly and does not
+// bring it down to compression-many.
+func (td *TDigest) recluster() {
+	old := td.centroids
+	rand.Shuffle(len(old), func(i, j int) { old[i], old[j] = old[j], old[i] })
+
+	td.centroids = nil
+	td.totalWeight = 0
+	for _, c := range old {
+		td.insert(c.mean, c.weight)
+	}
+}
+
+// Quantile returns an estimate of the value at quantile q (0 <= q <= 1) by
+// walking centroids in sorted order and interpolating between the two that
+// straddle q*totalWeight.
+func (td *TDigest) Quantile(q float64) float64 {
+	switch len(td.centroids) {
+	case 0:
+		return 0
+	case 1:
+		return td.centroids[0].mean
+	}
+
+	target := q * td.totalWeight
+	cumulative := 0.0
+	for i, c := range td.centroids {
+		next := cumulative + c.weight
+		if next >= target || i == len(td.centroids)-1 {
+			if i == 0 {
+				return c.mean
+			}
+			prev := td.centroids[i-1]
+			span := next - cumulative
+			if span == 0 {
+				return c.mean
+			}
+			frac := (target - cumulative) / span
+			return prev.mean + frac*(c.mean-prev.mean)
+		}
+		cumulative = next
+	}
+	return td.centroids[len(td.centroids)-1].mean
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,102 @@
+package tdigest
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// TestQuantileAgainstBruteForceOracle adds samples from a few known
+// distributions to a TDigest and checks its Quantile estimates against a
+// brute-force oracle that sorts the full sample set and interpolates
+// directly, within a tolerance scaled to each distribution's range.
+func TestQuantileAgainstBruteForceOracle(t *testing.T) {
+	cases := []struct {
+		name      string
+		n         int
+		generate  func(r *rand.Rand) float64
+		tolerance float64 // max allowed error, as a fraction of the sample range
+	}{
+		{
+			name:      "uniform",
+			n:         5000,
+			generate:  func(r *rand.Rand) float64 { return r.Float64() * 1000 },
+			tolerance: 0.02,
+		},
+		{
+			name:      "exponential",
+			n:         5000,
+			generate:  func(r *rand.Rand) float64 { return r.ExpFloat64() * 100 },
+			tolerance: 0.05,
+		},
+		{
+			name: "bimodal",
+			n:    5000,
+			generate: func(r *rand.Rand) float64 {
+				if r.Float64() < 0.5 {
+					return r.NormFloat64()*5 + 10
+				}
+				return r.NormFloat64()*5 + 500
+			},
+			tolerance: 0.03,
+		},
+	}
+
+	quantiles := []float64{0.5, 0.9, 0.95, 0.99}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(42))
+			samples := make([]float64, tc.n)
+			for i := range samples {
+				samples[i] = tc.generate(r)
+			}
+
+			digest := New(100)
+			for _, s := range samples {
+				digest.Add(s)
+			}
+
+			sorted := append([]float64(nil), samples...)
+			sort.Float64s(sorted)
+			dataRange := sorted[len(sorted)-1] - sorted[0]
+
+			for _, q := range quantiles {
+				want := bruteForceQuantile(sorted, q)
+				got := digest.Quantile(q)
+				if diff := math.Abs(got - want); diff > tc.tolerance*dataRange {
+					t.Errorf("quantile %.2f: got %.2f, oracle wants %.2f (diff %.2f exceeds tolerance %.2f)",
+						q, got, want, diff, tc.tolerance*dataRange)
+				}
+			}
+		})
+	}
+}
+
+// bruteForceQuantile is the oracle: it interpolates linearly between the two
+// samples straddling q in the fully sorted data, the same definition of
+// "quantile" TDigest.Quantile approximates.
+func bruteForceQuantile(sorted []float64, q float64) float64 {
+	idx := q * float64(len(sorted)-1)
+	lo := int(idx)
+	hi := lo + 1
+	if hi >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	frac := idx - float64(lo)
+	return sorted[lo] + frac*(sorted[hi]-sorted[lo])
+}
+
+func TestMaxAndCount(t *testing.T) {
+	digest := New(100)
+	for _, v := range []float64{3, 1, 4, 1, 5, 9, 2, 6} {
+		digest.Add(v)
+	}
+	if digest.Count() != 8 {
+		t.Errorf("Count() = %d, want 8", digest.Count())
+	}
+	if digest.Max() != 9 {
+		t.Errorf("Max() = %v, want 9", digest.Max())
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"go.viam.com/rdk/robot/client"
+)
+
+// maxConcurrentComponentTests bounds how many ComponentTests run at once so
+// a long component list doesn't overwhelm the machine under test.
+const maxConcurrentComponentTests = 4
+
+// runComponentTests runs each test against machine using a bounded worker
+// pool, so total runtime doesn't scale linearly with the number of
+// components. It returns a map of component name to its resulting APIStats.
+func runComponentTests(ctx context.Context, machine *client.RobotClient, tests []ComponentTest) map[string]APIStats {
+	results := make(map[string]APIStats, len(tests))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, maxConcurrentComponentTests)
+	for _, t := range tests {
+		wg.Add(1)
+		go func(t ComponentTest) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			stats := t.Run(ctx, machine)
+
+			mu.Lock()
+			results[t.Name()] = stats
+			mu.Unlock()
+		}(t)
+	}
+	wg.Wait()
+
+	return results
+}